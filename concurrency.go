@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/vinxi/layer.v0"
+)
+
+// ConcurrencyLimitResponder is used as the default function to respond when
+// the in-flight limit is reached. You can customize it via
+// ConcurrencyLimiter.SetResponder(fn).
+var ConcurrencyLimitResponder = func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(503)
+	w.Write([]byte("Service Unavailable"))
+}
+
+// ConcurrencyLimiter implements an in-flight request limiter middleware.
+// Unlike Limiter, which caps requests per second, ConcurrencyLimiter caps
+// how many requests may be handled at the same time, protecting against
+// slow-handler pile-ups regardless of the incoming rate.
+type ConcurrencyLimiter struct {
+	// sem is a buffered channel used as a counting semaphore: a slot is
+	// acquired on entry and released once the request has been served.
+	sem chan struct{}
+	// maxInFlight stores the configured semaphore capacity.
+	maxInFlight int
+	// inFlight tracks the number of requests currently being served.
+	inFlight int32
+	// maxWait, when non-zero, lets a request block for up to this long
+	// for a free slot instead of being rejected immediately.
+	maxWait time.Duration
+	// responder stores the responder function used when no slot is
+	// available within maxWait.
+	responder http.HandlerFunc
+	// whitelist provides the Filter/Exception machinery, shared with Limiter.
+	whitelist
+}
+
+// NewConcurrencyLimiter creates a concurrency limiter middleware which
+// allows at most maxInFlight requests to be served at the same time,
+// rejecting the rest with a 503 by default.
+func NewConcurrencyLimiter(maxInFlight int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		sem:         make(chan struct{}, maxInFlight),
+		maxInFlight: maxInFlight,
+		responder:   ConcurrencyLimitResponder,
+	}
+}
+
+// SetMaxWait configures how long a request should block waiting for a free
+// slot before being rejected. Defaults to zero, i.e. reject immediately.
+func (l *ConcurrencyLimiter) SetMaxWait(d time.Duration) {
+	l.maxWait = d
+}
+
+// SetResponder sets a custom function to reply when no slot is available.
+func (l *ConcurrencyLimiter) SetResponder(fn http.HandlerFunc) {
+	l.responder = fn
+}
+
+// Register registers the middleware handler.
+func (l *ConcurrencyLimiter) Register(mw layer.Middleware) {
+	mw.UsePriority("request", layer.TopHead, l.LimitHTTP)
+}
+
+// InFlight reports how many requests are currently being served.
+func (l *ConcurrencyLimiter) InFlight() int {
+	return int(atomic.LoadInt32(&l.inFlight))
+}
+
+// MaxInFlight reports the configured concurrency cap.
+func (l *ConcurrencyLimiter) MaxInFlight() int {
+	return l.maxInFlight
+}
+
+// LimitHTTP limits an incoming HTTP request.
+// If some filter passes, the request won't be limited.
+// This method is used internally, but made public for public testing.
+func (l *ConcurrencyLimiter) LimitHTTP(h http.Handler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.skip(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// Apply the concurrency limiter
+		l.limit(w, r, h)
+	}
+}
+
+// limit acquires a semaphore slot for the given HTTP request, waiting up to
+// maxWait if configured, and replies with an error if none becomes free.
+func (l *ConcurrencyLimiter) limit(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	select {
+	case l.sem <- struct{}{}:
+	default:
+		if l.maxWait <= 0 || !l.acquireWithTimeout() {
+			l.responder(w, r)
+			return
+		}
+	}
+
+	atomic.AddInt32(&l.inFlight, 1)
+	defer func() {
+		atomic.AddInt32(&l.inFlight, -1)
+		<-l.sem
+	}()
+
+	h.ServeHTTP(w, r)
+}
+
+// acquireWithTimeout blocks for up to maxWait trying to acquire a slot,
+// returning whether it succeeded.
+func (l *ConcurrencyLimiter) acquireWithTimeout() bool {
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterRejectsBeyondMaxInFlight(t *testing.T) {
+	l := NewConcurrencyLimiter(1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	handler := l.LimitHTTP(slow)
+
+	go func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 503 {
+		t.Fatalf("expected a second concurrent request to be rejected with 503, got %d", rec.Code)
+	}
+	if got := l.InFlight(); got != 1 {
+		t.Fatalf("expected 1 in-flight request, got %d", got)
+	}
+
+	close(release)
+}
+
+func TestConcurrencyLimiterMaxWaitAdmitsOnceSlotFrees(t *testing.T) {
+	l := NewConcurrencyLimiter(1)
+	l.SetMaxWait(100 * time.Millisecond)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.LimitHTTP(slow)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-started
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	rec := httptest.NewRecorder()
+	l.LimitHTTP(fast)(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected the waiting request to be admitted once a slot freed up, got %d", rec.Code)
+	}
+
+	wg.Wait()
+}
+
+func TestConcurrencyLimiterMaxInFlight(t *testing.T) {
+	l := NewConcurrencyLimiter(7)
+	if got := l.MaxInFlight(); got != 7 {
+		t.Fatalf("expected MaxInFlight to report 7, got %d", got)
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// NewDynamicLimiter creates a rate limiter middleware backed by
+// golang.org/x/time/rate instead of the fixed juju/ratelimit bucket. Unlike
+// NewRateLimiter/NewTimeLimiter, the resulting Limiter can have its rate and
+// burst reconfigured at runtime via SetRate/SetBurst, without dropping
+// in-flight connections.
+func NewDynamicLimiter(ratePerSecond float64, capacity int) *Limiter {
+	return &Limiter{
+		responder: RateLimitResponder,
+		dynamic:   rate.NewLimiter(rate.Limit(ratePerSecond), capacity),
+	}
+}
+
+// SetRate atomically updates the allowed rate (in requests per second) of a
+// Limiter created via NewDynamicLimiter. It's a no-op on limiters created
+// with any other constructor.
+func (l *Limiter) SetRate(ratePerSecond float64) {
+	if l.dynamic != nil {
+		l.dynamic.SetLimit(rate.Limit(ratePerSecond))
+	}
+}
+
+// SetBurst atomically updates the burst capacity of a Limiter created via
+// NewDynamicLimiter. It's a no-op on limiters created with any other
+// constructor.
+func (l *Limiter) SetBurst(capacity int) {
+	if l.dynamic != nil {
+		l.dynamic.SetBurst(capacity)
+	}
+}
+
+// limitWithDynamic applies the rate limiter using the x/time/rate engine.
+func (l *Limiter) limitWithDynamic(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	now := time.Now()
+	reservation := l.dynamic.ReserveN(now, 1)
+	retryAfter := reservation.DelayFrom(now)
+	allowed := reservation.OK() && retryAfter == 0
+
+	remaining := int64(l.dynamic.TokensAt(now))
+	if remaining < 0 {
+		remaining = 0
+	}
+	reset := now.Add(fillDuration(int64(l.dynamic.Burst())-remaining, float64(l.dynamic.Limit())))
+
+	l.writeHeaders(w, int64(l.dynamic.Burst()), remaining, reset)
+
+	if !allowed {
+		reservation.Cancel()
+		l.respond(w, r, retryAfter)
+		return
+	}
+
+	h.ServeHTTP(w, r)
+}
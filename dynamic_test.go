@@ -0,0 +1,30 @@
+package ratelimit
+
+import "testing"
+
+func TestDynamicLimiterSetRateChangesLimit(t *testing.T) {
+	l := NewDynamicLimiter(1, 1)
+
+	l.SetRate(100)
+	if got := float64(l.dynamic.Limit()); got != 100 {
+		t.Fatalf("expected SetRate to update the limiter's rate to 100, got %v", got)
+	}
+}
+
+func TestDynamicLimiterSetBurstChangesCapacity(t *testing.T) {
+	l := NewDynamicLimiter(1, 1)
+
+	l.SetBurst(5)
+	if got := l.dynamic.Burst(); got != 5 {
+		t.Fatalf("expected SetBurst to update the limiter's burst to 5, got %d", got)
+	}
+}
+
+func TestDynamicLimiterSetRateSetBurstNoopWithoutDynamic(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+
+	// Neither call should panic on a Limiter that wasn't built via
+	// NewDynamicLimiter, since l.dynamic is nil.
+	l.SetRate(100)
+	l.SetBurst(5)
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// gcraStore is a Store implementation of the generic cell rate algorithm
+// (GCRA), as used by throttled. Unlike a token bucket it only needs to
+// track a single "theoretical arrival time" (tat) per key, giving O(1)
+// memory per key with smooth burst control and a precise Retry-After.
+type gcraStore struct {
+	mu sync.Mutex
+	// tat stores the theoretical arrival time of the next request, per key.
+	tat map[string]time.Time
+	// emissionInterval is the minimum time that should separate two
+	// requests once the burst allowance is exhausted (period / count).
+	emissionInterval time.Duration
+	// burstOffset is the emissionInterval expressed in terms of the burst
+	// size, i.e. how far tat is allowed to run ahead of now.
+	burstOffset time.Duration
+	// burst is the maximum number of requests allowed in a single burst.
+	burst int64
+}
+
+// newGCRAStore creates a GCRA Store which allows count requests per period,
+// with up to burst requests admitted back-to-back before the steady
+// emissionInterval kicks in.
+func newGCRAStore(period time.Duration, count int, burst int) *gcraStore {
+	emissionInterval := period / time.Duration(count)
+	return &gcraStore{
+		tat:              make(map[string]time.Time),
+		emissionInterval: emissionInterval,
+		burstOffset:      emissionInterval * time.Duration(burst),
+		burst:            int64(burst),
+	}
+}
+
+// Take implements the Store interface.
+func (s *gcraStore) Take(key string, n int64) (bool, int64, time.Time, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tat := s.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(s.emissionInterval * time.Duration(n))
+	allowAt := newTat.Add(-s.burstOffset)
+
+	if allowAt.After(now) {
+		// Denied: don't commit newTat, so the request isn't charged.
+		// allowAt is the exact instant a slot will next be free.
+		return false, gcraRemaining(tat, now, s.emissionInterval, s.burst), allowAt, nil
+	}
+
+	s.tat[key] = newTat
+	return true, gcraRemaining(newTat, now, s.emissionInterval, s.burst), newTat, nil
+}
+
+// gcraRemaining estimates how many requests could be admitted back-to-back
+// right now, given tat. Shared by gcraStore and RedisGCRAStore so both
+// variants of the algorithm report remaining the same way.
+func gcraRemaining(tat, now time.Time, emissionInterval time.Duration, burst int64) int64 {
+	diff := tat.Sub(now)
+	if diff <= 0 {
+		return burst
+	}
+
+	used := int64(diff / emissionInterval)
+	if remaining := burst - used; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// NewGCRALimiter creates a rate limiter middleware implementing the generic
+// cell rate algorithm: count requests are allowed per period, with up to
+// burst requests admitted back-to-back. Denied requests receive a precise
+// Retry-After header computed from the theoretical arrival time.
+//
+// This constructor keeps its tat accounting in process memory, so it only
+// enforces the limit within a single vinxi instance. For a limit shared
+// across a cluster, build a Limiter around NewRedisGCRAStore and
+// NewDistributedLimiter instead, since both implement the same Store
+// abstraction.
+func NewGCRALimiter(period time.Duration, count int, burst int) *Limiter {
+	return &Limiter{
+		responder:  RateLimitResponder,
+		store:      newGCRAStore(period, count, burst),
+		storeLimit: int64(burst),
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCRAStoreAllowsBurstThenDenies(t *testing.T) {
+	// 10 requests per 100ms => emissionInterval of 10ms, with a burst of 2.
+	store := newGCRAStore(100*time.Millisecond, 10, 2)
+
+	allowed, _, _, err := store.Take("k", 1)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !allowed {
+		t.Fatal("expected first request within burst to be allowed")
+	}
+
+	allowed, _, _, err = store.Take("k", 1)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !allowed {
+		t.Fatal("expected second request within burst to be allowed")
+	}
+
+	allowed, _, reset, err := store.Take("k", 1)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if allowed {
+		t.Fatal("expected third back-to-back request to exceed the burst and be denied")
+	}
+
+	retryAfter := time.Until(reset)
+	if retryAfter <= 0 {
+		t.Fatalf("expected a positive retry-after, got %s", retryAfter)
+	}
+
+	time.Sleep(retryAfter + 5*time.Millisecond)
+
+	allowed, _, _, err = store.Take("k", 1)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !allowed {
+		t.Fatal("expected request to be allowed again once a slot frees up")
+	}
+}
+
+func TestGCRAStoreKeysAreIndependent(t *testing.T) {
+	store := newGCRAStore(100*time.Millisecond, 10, 1)
+
+	if allowed, _, _, _ := store.Take("a", 1); !allowed {
+		t.Fatal("expected key \"a\" to be allowed")
+	}
+	if allowed, _, _, _ := store.Take("a", 1); allowed {
+		t.Fatal("expected key \"a\" burst of 1 to be exhausted")
+	}
+	if allowed, _, _, _ := store.Take("b", 1); !allowed {
+		t.Fatal("expected an unrelated key \"b\" to have its own, unexhausted burst")
+	}
+}
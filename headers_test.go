@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteHeadersReportsSecondsUntilReset(t *testing.T) {
+	l := &Limiter{}
+	rec := httptest.NewRecorder()
+
+	reset := time.Now().Add(5 * time.Second)
+	l.writeHeaders(rec, 10, 4, reset)
+
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "10" {
+		t.Fatalf("expected X-RateLimit-Limit 10, got %q", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "4" {
+		t.Fatalf("expected X-RateLimit-Remaining 4, got %q", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Reset"); got != "4" && got != "5" {
+		t.Fatalf("expected X-RateLimit-Reset to be seconds-until-reset (~5), got %q", got)
+	}
+
+	// Without UseStandardHeaders, the IETF draft headers must be absent.
+	if got := rec.Header().Get("RateLimit-Limit"); got != "" {
+		t.Fatalf("expected no RateLimit-Limit header when standard headers are disabled, got %q", got)
+	}
+}
+
+func TestWriteHeadersStandardHeadersMatchLegacyReset(t *testing.T) {
+	l := &Limiter{standardHeaders: true}
+	rec := httptest.NewRecorder()
+
+	reset := time.Now().Add(5 * time.Second)
+	l.writeHeaders(rec, 10, 4, reset)
+
+	legacy := rec.Header().Get("X-RateLimit-Reset")
+	standard := rec.Header().Get("RateLimit-Reset")
+	if legacy != standard {
+		t.Fatalf("expected X-RateLimit-Reset and RateLimit-Reset to use the same seconds-until-reset scale, got %q vs %q", legacy, standard)
+	}
+
+	if got := rec.Header().Get("RateLimit-Policy"); got != "10;w="+standard {
+		t.Fatalf("expected RateLimit-Policy to be \"10;w=%s\", got %q", standard, got)
+	}
+}
+
+func TestWriteHeadersClampsPastReset(t *testing.T) {
+	l := &Limiter{}
+	rec := httptest.NewRecorder()
+
+	l.writeHeaders(rec, 10, 10, time.Now().Add(-time.Second))
+
+	if got := rec.Header().Get("X-RateLimit-Reset"); got != "0" {
+		t.Fatalf("expected a reset time in the past to clamp to 0, got %q", got)
+	}
+}
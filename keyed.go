@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/juju/ratelimit"
+)
+
+// defaultMaxKeyedEntries bounds the number of distinct buckets a keyed
+// limiter created via NewKeyedLimiter will keep around, to avoid unbounded
+// memory growth when keyFn produces a large or unbounded key space.
+const defaultMaxKeyedEntries = 10000
+
+// keyedBucketStore is a Store implementation that maintains one token
+// bucket per key, evicting the least recently used entries once maxEntries
+// is reached.
+type keyedBucketStore struct {
+	mu       sync.Mutex
+	cache    *lru.Cache
+	rate     float64
+	capacity int64
+}
+
+func newKeyedBucketStore(rate float64, capacity int64, maxEntries int) *keyedBucketStore {
+	return &keyedBucketStore{
+		cache:    lru.New(maxEntries),
+		rate:     rate,
+		capacity: capacity,
+	}
+}
+
+// Take implements the Store interface. It's all-or-nothing: if fewer than n
+// tokens are available, nothing is deducted and the request is denied,
+// matching RedisStore's semantics.
+func (s *keyedBucketStore) Take(key string, n int64) (bool, int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bucket *ratelimit.Bucket
+	if v, ok := s.cache.Get(lru.Key(key)); ok {
+		bucket = v.(*ratelimit.Bucket)
+	} else {
+		bucket = ratelimit.NewBucketWithRate(s.rate, s.capacity)
+		s.cache.Add(lru.Key(key), bucket)
+	}
+
+	allowed := bucket.Available() >= n
+	if allowed {
+		bucket.TakeAvailable(n)
+	}
+
+	remaining := bucket.Available()
+	reset := time.Now().Add(fillDuration(s.capacity-remaining, s.rate))
+	return allowed, remaining, reset, nil
+}
+
+// NewKeyedLimiter creates a rate limiter middleware that maintains an
+// independent rate/capacity bucket per key, as computed by keyFn. Buckets
+// are kept in an LRU capped at defaultMaxKeyedEntries; use
+// NewKeyedLimiterSize to configure the cap explicitly.
+func NewKeyedLimiter(rate float64, capacity int64, keyFn func(r *http.Request) string) *Limiter {
+	return NewKeyedLimiterSize(rate, capacity, keyFn, defaultMaxKeyedEntries)
+}
+
+// NewKeyedLimiterSize is like NewKeyedLimiter but lets callers configure the
+// maximum number of distinct keys to track at once.
+func NewKeyedLimiterSize(rate float64, capacity int64, keyFn func(r *http.Request) string, maxEntries int) *Limiter {
+	return &Limiter{
+		responder:  RateLimitResponder,
+		store:      newKeyedBucketStore(rate, capacity, maxEntries),
+		keyFn:      keyFn,
+		storeLimit: capacity,
+	}
+}
+
+// KeyByIP partitions traffic by the client's remote IP address, ignoring
+// the port.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader returns a key function that partitions traffic by the value
+// of the given request header, e.g. an API key sent as "X-API-Key".
+func KeyByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByRoute partitions traffic by the request path, so each route gets its
+// own independent limit.
+func KeyByRoute(r *http.Request) string {
+	return r.URL.Path
+}
+
+// KeyByRemoteAddrRespectingXFF partitions traffic by client IP, preferring
+// the first address in X-Forwarded-For when present (i.e. the original
+// client behind any proxies) and falling back to the direct remote address
+// otherwise. Trusting X-Forwarded-For blindly lets clients spoof their key
+// through a reverse proxy that doesn't strip or override it, so this should
+// only be used behind a proxy you control that sets the header itself.
+func KeyByRemoteAddrRespectingXFF(r *http.Request) string {
+	xff := r.Header.Get("X-Forwarded-For")
+	if xff == "" {
+		return KeyByIP(r)
+	}
+
+	parts := strings.Split(xff, ",")
+	ip := strings.TrimSpace(parts[0])
+	if ip == "" || net.ParseIP(ip) == nil {
+		return KeyByIP(r)
+	}
+	return ip
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import "testing"
+
+func TestKeyedBucketStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	// capacity of 1 token at a slow rate means an exhausted bucket stays
+	// exhausted for the duration of this test unless it gets evicted and
+	// recreated from scratch.
+	store := newKeyedBucketStore(1, 1, 2)
+
+	if allowed, _, _, _ := store.Take("a", 1); !allowed {
+		t.Fatal("expected first request for key \"a\" to be allowed")
+	}
+	if allowed, _, _, _ := store.Take("b", 1); !allowed {
+		t.Fatal("expected first request for key \"b\" to be allowed")
+	}
+
+	// Touch "a" again so it's the most recently used entry, leaving "b"
+	// as the least recently used one.
+	store.Take("a", 1)
+
+	// Adding a third key exceeds maxEntries (2), so the LRU entry ("b")
+	// should be evicted.
+	if allowed, _, _, _ := store.Take("c", 1); !allowed {
+		t.Fatal("expected first request for key \"c\" to be allowed")
+	}
+
+	// "b" was evicted, so this should get a fresh bucket at full
+	// capacity rather than reusing the exhausted one.
+	allowed, _, _, err := store.Take("b", 1)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !allowed {
+		t.Fatal("expected key \"b\" to have been evicted and recreated with a fresh bucket")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/juju/ratelimit"
+	"golang.org/x/time/rate"
 	"gopkg.in/vinxi/layer.v0"
 )
 
@@ -25,17 +26,86 @@ var RateLimitResponder = func(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Too Many Requests"))
 }
 
+// RetryResponder is like a Filter/Exception-style callback but for
+// responding when the rate limit is reached, receiving the Retry-After
+// duration computed for the denied request (see limitWithStore for how it is
+// derived from a Store). Set via Limiter.SetRetryResponder.
+type RetryResponder func(w http.ResponseWriter, r *http.Request, retryAfter time.Duration)
+
+// whitelist holds the Filter/Exception registration and evaluation logic
+// shared by Limiter and ConcurrencyLimiter, so both middlewares compose the
+// same way around whatever traffic they're asked to skip.
+type whitelist struct {
+	// filters stores a list of filters to determine if should apply the limiter.
+	filters []Filter
+	// exceptions stores a list of exceptions to determine if should not apply the limiter.
+	exceptions []Exception
+}
+
+// Filter registers a new limiter whitelist filter.
+// If the filter matches, the traffic won't be limited.
+func (wl *whitelist) Filter(fn ...Filter) {
+	wl.filters = append(wl.filters, fn...)
+}
+
+// Exception registers whitelist exception.
+// If the exception function matches, the traffic won't be limited.
+func (wl *whitelist) Exception(fn ...Exception) {
+	wl.exceptions = append(wl.exceptions, fn...)
+}
+
+// skip reports whether r should bypass the limiter entirely, per the
+// registered exceptions/filters.
+func (wl *whitelist) skip(r *http.Request) bool {
+	// Run exceptions to ignore the limiter, if necessary
+	for _, exception := range wl.exceptions {
+		if exception(r) {
+			return true
+		}
+	}
+
+	// Pass filters to determine if should apply the limiter.
+	// All the filtes must pass to apply the limiter.
+	for _, filter := range wl.filters {
+		if !filter(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Limiter implements a token bucket rate limiter middleware.
 // Rate limiter can support multiple rate limit strategies, such as time based limiter.
 type Limiter struct {
 	// bucket stores the ratelimit.Bucket limiter currently used.
 	bucket *ratelimit.Bucket
+	// dynamic, when present, delegates accounting to a golang.org/x/time/rate
+	// limiter instead of bucket, allowing the rate and burst to be changed
+	// at runtime via SetRate/SetBurst. Set by NewDynamicLimiter.
+	dynamic *rate.Limiter
+	// store, when present, delegates token accounting to an external
+	// Store implementation instead of the in-process bucket. This is
+	// what powers NewDistributedLimiter.
+	store Store
+	// keyFn computes the partition key used to look up a bucket in
+	// store. Defaults to a single shared "global" key when nil.
+	keyFn func(r *http.Request) string
+	// storeLimit mirrors the rate limiter capacity configured for store,
+	// since there's no local bucket to query it from.
+	storeLimit int64
 	// responser stores the responder function used when the rate limit is reached.
 	responder http.HandlerFunc
-	// filters stores a list of filters to determine if should apply the rate limiter.
-	filters []Filter
-	// exceptions stores a list of exceptions to determine if should not apply the rate limiter.
-	exceptions []Exception
+	// retryResponder, when set, is used instead of responder so the caller
+	// can render the computed Retry-After into a custom body (e.g. JSON).
+	retryResponder RetryResponder
+	// standardHeaders controls whether the IETF draft RateLimit/
+	// RateLimit-Policy headers are emitted alongside the X-RateLimit-*
+	// ones. See Limiter.UseStandardHeaders.
+	standardHeaders bool
+	// whitelist provides the Filter/Exception machinery, shared with
+	// ConcurrencyLimiter.
+	whitelist
 }
 
 // NewTimeLimiter creates a new time based rate limiter middleware.
@@ -55,21 +125,40 @@ func NewRateLimiter(rate float64, capacity int64) *Limiter {
 	}
 }
 
+// NewDistributedLimiter creates a rate limiter middleware which delegates
+// token accounting to store, so the limit is enforced consistently across
+// multiple vinxi instances sharing the same store (e.g. RedisStore).
+// keyFn partitions traffic into independent buckets, for example by client
+// IP, API key or route; pass nil to share a single global bucket.
+func NewDistributedLimiter(store Store, rate float64, capacity int64, keyFn func(r *http.Request) string) *Limiter {
+	return &Limiter{
+		responder:  RateLimitResponder,
+		store:      store,
+		keyFn:      keyFn,
+		storeLimit: capacity,
+	}
+}
+
 // SetResponder sets a custom function to reply in case of rate limit reached.
 func (l *Limiter) SetResponder(fn http.HandlerFunc) {
 	l.responder = fn
 }
 
-// Filter registers a new rate limiter whitelist filter.
-// If the filter matches, the traffic won't be limited.
-func (l *Limiter) Filter(fn ...Filter) {
-	l.filters = append(l.filters, fn...)
+// SetRetryResponder sets a custom function to reply in case of rate limit
+// reached, like SetResponder, but also receiving the Retry-After duration
+// computed for the denied request so it can be rendered into the response
+// body (e.g. a JSON error). Takes precedence over the responder set via
+// SetResponder when present.
+func (l *Limiter) SetRetryResponder(fn RetryResponder) {
+	l.retryResponder = fn
 }
 
-// Exception registers whitelist exception.
-// If the exception function matches, the traffic won't be limited.
-func (l *Limiter) Exception(fn ...Exception) {
-	l.exceptions = append(l.exceptions, fn...)
+// UseStandardHeaders enables emitting the IETF draft rate limit headers
+// (RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy), in addition to the X-RateLimit-* ones, so clients
+// written against the modern spec work without custom parsing.
+func (l *Limiter) UseStandardHeaders(enabled bool) {
+	l.standardHeaders = enabled
 }
 
 // Register registers the middleware handler.
@@ -82,21 +171,9 @@ func (l *Limiter) Register(mw layer.Middleware) {
 // This method is used internally, but made public for public testing.
 func (l *Limiter) LimitHTTP(h http.Handler) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Run exceptions to ignore the limiter, if necessary
-		for _, exception := range l.exceptions {
-			if exception(r) {
-				h.ServeHTTP(w, r)
-				return
-			}
-		}
-
-		// Pass filters to determine if should apply the limiter.
-		// All the filtes must pass to apply the limiter.
-		for _, filter := range l.filters {
-			if !filter(r) {
-				h.ServeHTTP(w, r)
-				return
-			}
+		if l.skip(r) {
+			h.ServeHTTP(w, r)
+			return
 		}
 
 		// Apply the rate limiter
@@ -107,15 +184,25 @@ func (l *Limiter) LimitHTTP(h http.Handler) func(w http.ResponseWriter, r *http.
 // limit applies the rate limiter to the given HTTP request.
 // If the rate exceeds, will reply with an error.
 func (l *Limiter) limit(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	if l.dynamic != nil {
+		l.limitWithDynamic(w, r, h)
+		return
+	}
+
+	if l.store != nil {
+		l.limitWithStore(w, r, h)
+		return
+	}
+
 	available := l.bucket.TakeAvailable(1)
+	remaining := int64(l.remaining())
+	reset := time.Now().Add(fillDuration(int64(l.capacity())-remaining, l.bucket.Rate()))
 
-	headers := w.Header()
-	headers.Set("X-RateLimit-Limit", strconv.Itoa(l.capacity()))
-	headers.Set("X-RateLimit-Remaining", strconv.Itoa(l.remaining()))
+	l.writeHeaders(w, int64(l.capacity()), remaining, reset)
 
 	// If tokens are not available, reply with error, usually with 429
 	if available == 0 {
-		l.responder(w, r)
+		l.respond(w, r, fillDuration(1, l.bucket.Rate()))
 		return
 	}
 
@@ -123,6 +210,80 @@ func (l *Limiter) limit(w http.ResponseWriter, r *http.Request, h http.Handler)
 	h.ServeHTTP(w, r)
 }
 
+// writeHeaders sets the X-RateLimit-* headers (and, if enabled via
+// UseStandardHeaders, the IETF draft RateLimit/RateLimit-Policy ones) for a
+// request accounted against limit/remaining/reset. X-RateLimit-Reset and
+// RateLimit-Reset both report seconds until reset, not an absolute
+// timestamp: normally the time at which the bucket refills to capacity, or,
+// for a request denied by a GCRA store, the instant the next request is
+// admitted (see Store.Take).
+func (l *Limiter) writeHeaders(w http.ResponseWriter, limit, remaining int64, reset time.Time) {
+	secondsUntilReset := int64(time.Until(reset).Seconds())
+	if secondsUntilReset < 0 {
+		secondsUntilReset = 0
+	}
+
+	headers := w.Header()
+	headers.Set("X-RateLimit-Limit", strconv.FormatInt(limit, 10))
+	headers.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	headers.Set("X-RateLimit-Reset", strconv.FormatInt(secondsUntilReset, 10))
+
+	if !l.standardHeaders {
+		return
+	}
+
+	headers.Set("RateLimit-Limit", strconv.FormatInt(limit, 10))
+	headers.Set("RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	headers.Set("RateLimit-Reset", strconv.FormatInt(secondsUntilReset, 10))
+	headers.Set("RateLimit-Policy", strconv.FormatInt(limit, 10)+`;w=`+strconv.FormatInt(secondsUntilReset, 10))
+}
+
+// respond replies to a denied request, setting Retry-After and preferring
+// the retryResponder set via SetRetryResponder when present.
+func (l *Limiter) respond(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)+1))
+	}
+
+	if l.retryResponder != nil {
+		l.retryResponder(w, r, retryAfter)
+		return
+	}
+	l.responder(w, r)
+}
+
+// limitWithStore applies the rate limiter using the configured Store,
+// partitioning traffic via keyFn. Used by NewDistributedLimiter and any
+// other constructor that needs accounting shared across a cluster.
+// A denied request's Retry-After is the time until the reset the store
+// returns: for the GCRA stores that is when the next request is admitted;
+// for the token bucket stores (MemoryStore, RedisStore and the keyed
+// limiter) it is when the bucket is full again, which is longer than the
+// wait for a single token whenever the capacity is above one.
+func (l *Limiter) limitWithStore(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	key := "global"
+	if l.keyFn != nil {
+		key = l.keyFn(r)
+	}
+
+	allowed, remaining, reset, err := l.store.Take(key, 1)
+	if err != nil {
+		// Fail open: a misbehaving store shouldn't take the whole
+		// service down, just skip limiting for this request.
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	l.writeHeaders(w, l.storeLimit, remaining, reset)
+
+	if !allowed {
+		l.respond(w, r, reset.Sub(time.Now()))
+		return
+	}
+
+	h.ServeHTTP(w, r)
+}
+
 // capacity is used to read the current bucket capacity.
 func (l *Limiter) capacity() int {
 	return int(l.bucket.Capacity())
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/ratelimit"
+)
+
+// Store represents the persistence abstraction used to keep token
+// accounting for a rate limiter. Implementations must be safe for
+// concurrent use, since they're typically shared across goroutines
+// handling requests, and in the distributed case across processes.
+type Store interface {
+	// Take attempts to take n tokens for the given key, returning whether
+	// the request is allowed, how many tokens remain available and the
+	// time at which the bucket will be refilled back to capacity. The GCRA
+	// stores (the one behind NewGCRALimiter and RedisGCRAStore) instead
+	// return, for a denied request, the instant the next request is admitted.
+	Take(key string, n int64) (allowed bool, remaining int64, reset time.Time, err error)
+}
+
+// MemoryStore is an in-process Store implementation backed by a map of
+// juju/ratelimit buckets, one per key. It's the simplest Store and is
+// mainly useful for single-instance deployments or for testing
+// NewDistributedLimiter without a real external store such as Redis.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*ratelimit.Bucket
+	rate     float64
+	capacity int64
+}
+
+// NewMemoryStore creates a new in-memory Store which hands out rate/capacity
+// token buckets on demand, one per key.
+func NewMemoryStore(rate float64, capacity int64) *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*ratelimit.Bucket),
+		rate:     rate,
+		capacity: capacity,
+	}
+}
+
+// Take implements the Store interface. It's all-or-nothing: if fewer than n
+// tokens are available, nothing is deducted and the request is denied,
+// matching RedisStore's semantics.
+func (s *MemoryStore) Take(key string, n int64) (bool, int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = ratelimit.NewBucketWithRate(s.rate, s.capacity)
+		s.buckets[key] = bucket
+	}
+
+	allowed := bucket.Available() >= n
+	if allowed {
+		bucket.TakeAvailable(n)
+	}
+
+	remaining := bucket.Available()
+	reset := time.Now().Add(fillDuration(s.capacity-remaining, s.rate))
+	return allowed, remaining, reset, nil
+}
+
+// fillDuration returns how long it would take to accumulate tokens
+// additional tokens at the given rate (tokens per second).
+func fillDuration(tokens int64, rate float64) time.Duration {
+	if tokens <= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(tokens) / rate * float64(time.Second))
+}
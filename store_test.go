@@ -0,0 +1,59 @@
+package ratelimit
+
+import "testing"
+
+func TestMemoryStoreAllowsUpToCapacity(t *testing.T) {
+	store := NewMemoryStore(1, 3)
+
+	allowed, remaining, _, err := store.Take("k", 3)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !allowed {
+		t.Fatal("expected a request within capacity to be allowed")
+	}
+	if remaining != 0 {
+		t.Fatalf("expected 0 tokens remaining, got %d", remaining)
+	}
+}
+
+func TestMemoryStoreDeniesPartialTakeWithoutDeducting(t *testing.T) {
+	store := NewMemoryStore(1, 3)
+
+	// Exhaust down to 1 token left.
+	if allowed, _, _, _ := store.Take("k", 2); !allowed {
+		t.Fatal("expected the first take to be allowed")
+	}
+
+	// Only 1 token remains; requesting 2 should be denied and must not
+	// partially deduct the 1 that's available.
+	allowed, remaining, _, err := store.Take("k", 2)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if allowed {
+		t.Fatal("expected a request for more tokens than available to be denied")
+	}
+	if remaining != 1 {
+		t.Fatalf("expected the 1 remaining token to be untouched by the denied take, got %d", remaining)
+	}
+
+	// The untouched token should still be takeable on its own.
+	if allowed, _, _, _ := store.Take("k", 1); !allowed {
+		t.Fatal("expected the preserved token to still be available")
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	store := NewMemoryStore(1, 1)
+
+	if allowed, _, _, _ := store.Take("a", 1); !allowed {
+		t.Fatal("expected key \"a\" to be allowed")
+	}
+	if allowed, _, _, _ := store.Take("a", 1); allowed {
+		t.Fatal("expected key \"a\" capacity of 1 to be exhausted")
+	}
+	if allowed, _, _, _ := store.Take("b", 1); !allowed {
+		t.Fatal("expected an unrelated key \"b\" to have its own, unexhausted bucket")
+	}
+}